@@ -17,20 +17,25 @@ import (
 
 // Breaker is a circuit breaker. The circuit breaker can be in one of
 // three states: closed (requests will be executed normally), open (requests will be
-// rejected immediately) or half-open (a single request will be used to determine whether
-// to move to the open or closed states)
+// rejected immediately) or half-open (one or more trial requests will be used to determine
+// whether to move to the open or closed states)
 // During normal operation the breaker is in the closed state. When a request fails a
 // counter is incremented. A successful request will reset the counter. When the failure
 // counter reaches a threshold, indicating a consecutive series of failures, the breaker
 // will trip and move to the open state.
 // In the open state all requests will fail immediately, returning the ErrCircuitOpen error.
 // A timer is started and after the reset timeout, the breaker will move into the half-open state.
-// In the half-open state the first call is used to trial the system. During this trial all
-// other requests will fail as though the breaker were in the open state. If the trialing
-// request succeeds the breaker is moved to the closed (normal) state. Otherwise the
-// breaker moves back to the open state and the reset timer is restarted.
+// In the half-open state up to HalfOpenMaxRequests calls are used to trial the system; any
+// further requests fail as though the breaker were in the open state. Once
+// HalfOpenSuccessThreshold of the trials succeed the breaker is moved to the closed
+// (normal) state. If any trial fails the breaker moves back to the open state immediately
+// and the reset timer is restarted.
 // In the closed and half-open states, a count of the number of concurrent requests is maintained. This
 // number rises above the configured maximum then the breaker will trip into the open state.
+// If WindowSize is set the breaker also maintains a rolling window of request outcomes,
+// divided into sub-buckets that age out as the window moves forward. Once the window has
+// seen MinimumRequests, the breaker trips open if the proportion of failing requests
+// reaches FailureRatio, even if those failures were not consecutive.
 type Breaker struct {
 	// Threshold controls the number of consecutive errors that are allowed before the
 	// circuit breaker trips open. If zero a default of 20 will be assumed.
@@ -44,6 +49,56 @@ type Breaker struct {
 	// should be put into the half-open state.  If zero a default of 10 seconds will be assumed.
 	ResetTimeout time.Duration
 
+	// AcquireTimeout is how long Do will wait for a free concurrency slot before giving
+	// up. If zero, Do returns ErrTooManyConcurrent immediately when the concurrency limit
+	// is reached, as before. If positive, Do instead queues briefly, and returns the
+	// caller's context error or ErrAcquireTimeout if no slot frees up in time.
+	AcquireTimeout time.Duration
+
+	// WindowSize is the duration of the rolling window over which request outcomes are
+	// counted towards FailureRatio. If zero the rolling failure-rate policy is disabled
+	// and only the consecutive-failure Threshold is used to decide when to trip.
+	//
+	// The rolling window's buckets are always kept in process memory, even when Store is
+	// set: unlike the consecutive failure count and half-open trial admission, they are
+	// not part of the StateStore contract. A Breaker combined with a shared Store and a
+	// non-zero WindowSize trips FailureRatio on each process's own slice of traffic, not
+	// the fleet's combined traffic.
+	WindowSize time.Duration
+
+	// MinimumRequests is the number of requests that must have been seen within the
+	// current window before FailureRatio is considered. This prevents a handful of
+	// failures in an otherwise quiet window from tripping the breaker. If zero a default
+	// of 10 will be assumed whenever WindowSize is non-zero.
+	MinimumRequests uint32
+
+	// FailureRatio is the proportion of failing requests within the window, in the range
+	// (0, 1], at or above which the breaker trips open. If zero a default of 0.5 will be
+	// assumed whenever WindowSize is non-zero.
+	FailureRatio float64
+
+	// HalfOpenMaxRequests is the number of trial requests admitted while the breaker is
+	// half-open. If zero a default of 1 will be assumed, preserving the original
+	// single-trial behaviour. A single failing trial reopens the circuit immediately,
+	// regardless of how many other trials succeeded.
+	HalfOpenMaxRequests uint32
+
+	// HalfOpenSuccessThreshold is the number of successful trials, out of
+	// HalfOpenMaxRequests, required before the breaker closes. If zero a default of 1
+	// will be assumed.
+	HalfOpenSuccessThreshold uint32
+
+	// Now returns the current time and is used to decide when to rotate the rolling
+	// window's buckets. If nil time.Now will be used. It exists as a seam so tests can
+	// control bucket rotation deterministically.
+	Now func() time.Time
+
+	// IsSuccessful classifies an error returned by the wrapped function. If it returns
+	// true the call is treated as a success and does not count towards the consecutive
+	// failure count or the rolling failure-rate window, even though the error is still
+	// returned to the caller. If nil every non-nil error is treated as a failure.
+	IsSuccessful func(error) bool
+
 	// OnOpen is a function that will be called when the circuit breaker trips open. If it
 	// is nil then it will be ignored.
 	OnOpen func(OpenReason)
@@ -52,33 +107,114 @@ type Breaker struct {
 	// is nil then it will be ignored.
 	OnClose func()
 
+	// OnReset is a function that will be called when the circuit breaker moves into the
+	// half-open state, before the first trial request is attempted. If it is nil then it
+	// will be ignored.
+	OnReset func()
+
+	// OnStateChange is a function that will be called on every state transition, in
+	// addition to OnOpen, OnClose and OnReset which remain supported for existing
+	// callers. r is only meaningful when to is StateOpen; it should be ignored for
+	// transitions to StateClosed or StateHalfOpen. If nil then it will be ignored.
+	OnStateChange func(from, to State, r OpenReason)
+
+	// Store holds the breaker's state: which of closed, open or half-open it is in, its
+	// consecutive failure count, and how many half-open trial slots and successes have
+	// been claimed. If nil an in-process store is used, preserving the breaker's original
+	// behaviour. A StateStore backed by Redis or another shared store lets a fleet of
+	// instances guarding the same upstream share one logical breaker instead of each
+	// tripping and probing independently. This sharing does not extend to WindowSize's
+	// rolling failure-rate window, which is always process-local; see WindowSize.
+	Store StateStore
+
 	// mu ensures only one state transition can occur at a time
 	mu     sync.Mutex
 	initer sync.Once
 
-	// Current state of the circuit breaker: closed, open, half-open
-	state uint32
-
-	// A count of consecutive failures
-	failures uint32
+	// store is the StateStore actually used at runtime: either Store, or the default
+	// in-memory implementation if Store was left nil.
+	store StateStore
 
 	// handles limit the number of concurrent requests
 	handles chan struct{}
 
-	// Keeps track of whether the circuit has trialed a request in the half-open state
-	attemptedTrial uint32
+	// totalSuccesses and totalFailures count executed requests for Counts. They only
+	// track outcomes of calls that were actually executed, not calls rejected because
+	// the circuit was open or too many were already in flight.
+	totalSuccesses uint64
+	totalFailures  uint64
+
+	// concurrentInFlight is the number of requests currently executing, for Counts.
+	concurrentInFlight uint32
+
+	// bucketMu guards the rolling window buckets used by the failure-rate policy
+	bucketMu sync.Mutex
+
+	// buckets holds the per-sub-window success/failure counts that make up the rolling window
+	buckets []windowBucket
+
+	// bucketIndex is the index of the bucket currently being written to
+	bucketIndex int
+
+	// bucketStart is the time at which the current bucket started collecting
+	bucketStart time.Time
+}
+
+// numBuckets is the number of sub-buckets the rolling window is divided into. A request's
+// outcome is always recorded in the most recent bucket; as time passes, older buckets roll
+// out of the window and their counts are discarded.
+const numBuckets = 10
+
+// windowBucket holds the counts of successful and failed requests recorded within a single
+// sub-division of the rolling window.
+type windowBucket struct {
+	successes uint32
+	failures  uint32
+}
+
+// State represents one of the three states a circuit breaker can be in.
+type State uint32
+
+const (
+	// StateClosed means requests are executed normally.
+	StateClosed State = iota
+
+	// StateOpen means requests are rejected immediately with ErrCircuitOpen.
+	StateOpen
+
+	// StateHalfOpen means trial requests are being used to decide whether to move to
+	// StateClosed or back to StateOpen.
+	StateHalfOpen
+)
+
+// String returns a human-readable name for the state, suitable for logging.
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
 }
 
 const (
-	closed   uint32 = 0
-	open     uint32 = 1
-	halfopen uint32 = 2
+	closed   = StateClosed
+	open     = StateOpen
+	halfopen = StateHalfOpen
 )
 
 const (
-	defaultThreshold    uint32        = 20
-	defaultConcurrency  uint32        = 10
-	defaultResetTimeout time.Duration = 10 * time.Second
+	defaultThreshold                uint32        = 20
+	defaultConcurrency              uint32        = 10
+	defaultResetTimeout             time.Duration = 10 * time.Second
+	defaultMinimumRequests          uint32        = 10
+	defaultFailureRatio             float64       = 0.5
+	defaultHalfOpenMaxRequests      uint32        = 1
+	defaultHalfOpenSuccessThreshold uint32        = 1
 )
 
 var (
@@ -87,8 +223,33 @@ var (
 
 	// ErrTooManyConcurrent is returned when a request would exceed the concurrency level of the breaker.
 	ErrTooManyConcurrent = errors.New("too many concurrent requests")
+
+	// ErrAcquireTimeout is returned when AcquireTimeout is set and no concurrency slot
+	// became free within it.
+	ErrAcquireTimeout = errors.New("timed out waiting for a concurrency slot")
 )
 
+// acquireCanceledError marks ctx having been done while attempt was still waiting for a
+// concurrency slot, before fn ran. It wraps ctx's own error so callers using errors.Is
+// still see context.Canceled or context.DeadlineExceeded, while Do can use errors.As to
+// tell this case apart from fn having actually run and returned a context-shaped error of
+// its own, which says nothing about the health of the concurrency-slot wait and must still
+// be counted as a normal result.
+type acquireCanceledError struct {
+	err error
+}
+
+func (e *acquireCanceledError) Error() string { return e.err.Error() }
+
+func (e *acquireCanceledError) Unwrap() error { return e.err }
+
+// isAcquireCanceled reports whether err indicates that ctx was done before fn had a chance
+// to run, as opposed to fn running and returning a context-shaped error itself.
+func isAcquireCanceled(err error) bool {
+	var acqErr *acquireCanceledError
+	return errors.As(err, &acqErr)
+}
+
 // An OpenReason indicates why the circuit breaker opened.
 type OpenReason int
 
@@ -101,45 +262,73 @@ const (
 
 	// OpenReasonTrial means the circuit opened because the trial request failed
 	OpenReasonTrial OpenReason = 2
+
+	// OpenReasonFailureRate means the circuit opened because the proportion of failing
+	// requests within the rolling window reached FailureRatio
+	OpenReasonFailureRate OpenReason = 3
 )
 
 // Do attempts to execute the supplied function. If the function is executed
 // any error it produces is treated as a failure, incrementing the breaker's
-// counter. The error, if any, is returned from Do. If there are too many
-// concurrent requests then fn will not be executed and ErrTooManyConcurrent
-// will be returned. When the breaker is in the open state then fn will not be
-// executed and ErrCircuitOpen error will be returned.
+// counter, unless IsSuccessful classifies that error as a success. The error,
+// if any, is returned from Do regardless of how it was classified. If there are
+// too many concurrent requests then fn will not be executed; if AcquireTimeout
+// is zero ErrTooManyConcurrent is returned immediately, otherwise Do waits up to
+// AcquireTimeout for a slot to free up and returns ctx's error or
+// ErrAcquireTimeout if none does; this case is never counted towards
+// TotalSuccesses or TotalFailures, since fn was never given the chance to run,
+// although in the half-open state it still reopens the circuit as a failed
+// trial. By contrast, an error fn
+// itself returns is always counted, even if it wraps context.Canceled or
+// context.DeadlineExceeded, since that says nothing about the concurrency-slot
+// wait and everything about the health of the wrapped call. When the breaker is
+// in the open state then fn will not be executed and ErrCircuitOpen error will
+// be returned.
 func (b *Breaker) Do(ctx context.Context, fn func() error) error {
 	b.initer.Do(b.init)
 
-	if ctx != nil {
-		// Check whether context has been cancelled
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// Check whether context has been cancelled
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
 	}
 
-	state := atomic.LoadUint32(&b.state)
+	state := b.store.LoadState()
 
 	switch state {
 	case closed:
 		// Try the action
-		err := b.attempt(fn)
-		switch err {
-		case nil:
+		err := b.attempt(ctx, fn)
+		switch {
+		case err == nil, b.isSuccessful(err):
 			// reset the consecutive failure count
-			atomic.StoreUint32(&b.failures, 0)
-			return nil
-		case ErrTooManyConcurrent:
+			atomic.AddUint64(&b.totalSuccesses, 1)
+			b.store.RecordSuccess()
+			b.recordRollingResult(true)
+			return err
+		case isAcquireCanceled(err):
+			// The caller gave up waiting for a concurrency slot before fn ran; this
+			// says nothing about the health of the wrapped call, so it is not counted
+			// either way.
+			return err
+		case err == ErrTooManyConcurrent, err == ErrAcquireTimeout:
 			b.open(OpenReasonConcurrency)
 			return err
 		default:
 			// record a failure
-			failures := atomic.AddUint32(&b.failures, 1)
+			atomic.AddUint64(&b.totalFailures, 1)
+			failures := b.store.RecordFailure()
 			if failures >= b.Threshold {
 				b.open(OpenReasonThreshold)
+				return err
+			}
+			if b.recordRollingResult(false) {
+				b.open(OpenReasonFailureRate)
 			}
 			return err
 		}
@@ -149,16 +338,34 @@ func (b *Breaker) Do(ctx context.Context, fn func() error) error {
 		return ErrCircuitOpen
 
 	case halfopen:
-		// Check if this is the first request since circuit was half opened
-		if atomic.CompareAndSwapUint32(&b.attemptedTrial, 0, 1) {
-			err := b.attempt(fn)
-			if err != nil {
+		// Check if this request has been admitted as one of the half-open trials
+		if b.store.TryAcquireTrial(b.HalfOpenMaxRequests) {
+			err := b.attempt(ctx, fn)
+			switch {
+			case err != nil && !b.isSuccessful(err):
+				// This also covers ctx being done before fn could run: an admitted
+				// trial slot that goes unused would otherwise be stranded until the
+				// process restarts, since nothing else releases it. Treating it as a
+				// failed trial reopens the circuit and restarts the reset timer,
+				// giving the store a fresh half-open period to admit trials into.
+				// Only a trial whose fn actually ran is added to totalFailures.
+				if !isAcquireCanceled(err) && err != ErrTooManyConcurrent && err != ErrAcquireTimeout {
+					atomic.AddUint64(&b.totalFailures, 1)
+				}
 				b.open(OpenReasonTrial)
 				return err
+			default:
+				atomic.AddUint64(&b.totalSuccesses, 1)
+				if from, closed := b.store.RecordTrialSuccess(b.HalfOpenSuccessThreshold); closed {
+					if b.OnClose != nil {
+						b.OnClose()
+					}
+					if b.OnStateChange != nil {
+						b.OnStateChange(from, StateClosed, 0)
+					}
+				}
+				return err
 			}
-
-			b.close()
-			return nil
 		}
 
 		return ErrCircuitOpen
@@ -167,14 +374,107 @@ func (b *Breaker) Do(ctx context.Context, fn func() error) error {
 	}
 }
 
-func (b *Breaker) attempt(fn func() error) error {
-	select {
-	case <-b.handles:
-	default:
-		return ErrTooManyConcurrent
+// isSuccessful reports whether err should be classified as a success for the purposes of
+// tripping the breaker. A nil error is never passed here. If IsSuccessful is nil, every
+// non-nil error is treated as a failure.
+func (b *Breaker) isSuccessful(err error) bool {
+	if err == nil {
+		return false
+	}
+	return b.IsSuccessful != nil && b.IsSuccessful(err)
+}
+
+// recordRollingResult records the outcome of a request against the rolling window and
+// reports whether the window's failure ratio has reached FailureRatio. It is a no-op,
+// always returning false, when WindowSize is zero.
+func (b *Breaker) recordRollingResult(success bool) bool {
+	if b.WindowSize == 0 {
+		return false
+	}
+
+	b.bucketMu.Lock()
+	defer b.bucketMu.Unlock()
+
+	b.rotateBucketsLocked()
+
+	if success {
+		b.buckets[b.bucketIndex].successes++
+	} else {
+		b.buckets[b.bucketIndex].failures++
+	}
+
+	var requests, failures uint32
+	for _, bk := range b.buckets {
+		requests += bk.successes + bk.failures
+		failures += bk.failures
+	}
+
+	if requests < b.MinimumRequests {
+		return false
+	}
+
+	return float64(failures)/float64(requests) >= b.FailureRatio
+}
+
+// rotateBucketsLocked advances the rolling window to the current time, clearing any
+// buckets that have aged out of the window. b.bucketMu must be held.
+func (b *Breaker) rotateBucketsLocked() {
+	bucketDuration := b.WindowSize / time.Duration(numBuckets)
+	if bucketDuration <= 0 {
+		// A WindowSize under numBuckets nanoseconds would otherwise truncate to a zero
+		// bucket duration; fall back to the smallest representable one instead of
+		// dividing by zero below.
+		bucketDuration = 1
+	}
+	now := b.Now()
+
+	elapsed := now.Sub(b.bucketStart)
+	if elapsed < bucketDuration {
+		return
+	}
+
+	steps := elapsed / bucketDuration
+	advance := numBuckets
+	if steps < numBuckets {
+		advance = int(steps)
+	}
+
+	for i := 0; i < advance; i++ {
+		b.bucketIndex = (b.bucketIndex + 1) % numBuckets
+		b.buckets[b.bucketIndex] = windowBucket{}
+	}
+
+	b.bucketStart = b.bucketStart.Add(steps * bucketDuration)
+}
+
+// attempt acquires a concurrency slot and, if one was acquired, executes fn. If
+// AcquireTimeout is zero it returns ErrTooManyConcurrent immediately when no slot is free.
+// Otherwise it waits up to AcquireTimeout, honouring ctx cancellation, and returns an
+// *acquireCanceledError wrapping ctx's error, or ErrAcquireTimeout, if no slot became free
+// in time. Once a slot is acquired fn's own result, whatever it is, is returned unwrapped.
+func (b *Breaker) attempt(ctx context.Context, fn func() error) error {
+	if b.AcquireTimeout <= 0 {
+		select {
+		case <-b.handles:
+		default:
+			return ErrTooManyConcurrent
+		}
+	} else {
+		timer := time.NewTimer(b.AcquireTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-b.handles:
+		case <-ctx.Done():
+			return &acquireCanceledError{err: ctx.Err()}
+		case <-timer.C:
+			return ErrAcquireTimeout
+		}
 	}
 
+	atomic.AddUint32(&b.concurrentInFlight, 1)
 	defer func() {
+		atomic.AddUint32(&b.concurrentInFlight, ^uint32(0))
 		b.handles <- struct{}{}
 	}()
 
@@ -182,45 +482,52 @@ func (b *Breaker) attempt(fn func() error) error {
 }
 
 func (b *Breaker) open(r OpenReason) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	if atomic.LoadUint32(&b.state) == open {
+	b.initer.Do(b.init)
+
+	from, ok := b.store.Open(r)
+	if !ok {
 		return
 	}
-	atomic.StoreUint32(&b.state, open)
-	atomic.StoreUint32(&b.failures, 0)
-	atomic.StoreUint32(&b.attemptedTrial, 0)
 	time.AfterFunc(b.ResetTimeout, b.reset)
 
 	if b.OnOpen != nil {
 		b.OnOpen(r)
 	}
+	if b.OnStateChange != nil {
+		b.OnStateChange(from, StateOpen, r)
+	}
 }
 
 func (b *Breaker) close() {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	if atomic.LoadUint32(&b.state) == closed {
+	b.initer.Do(b.init)
+
+	from, ok := b.store.Close()
+	if !ok {
 		return
 	}
-	atomic.StoreUint32(&b.state, closed)
-	atomic.StoreUint32(&b.failures, 0)
-	atomic.StoreUint32(&b.attemptedTrial, 0)
 	if b.OnClose != nil {
 		b.OnClose()
 	}
+	if b.OnStateChange != nil {
+		b.OnStateChange(from, StateClosed, 0)
+	}
 }
 
 // reset puts the breaker into half-open mode, usually after the reset timeout has passed
 func (b *Breaker) reset() {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	if atomic.LoadUint32(&b.state) == halfopen {
+	b.initer.Do(b.init)
+
+	from, ok := b.store.Reset()
+	if !ok {
 		return
 	}
-	atomic.StoreUint32(&b.state, halfopen)
-	atomic.StoreUint32(&b.failures, 0)
-	atomic.StoreUint32(&b.attemptedTrial, 0)
+
+	if b.OnReset != nil {
+		b.OnReset()
+	}
+	if b.OnStateChange != nil {
+		b.OnStateChange(from, StateHalfOpen, 0)
+	}
 }
 
 func (b *Breaker) init() {
@@ -239,23 +546,110 @@ func (b *Breaker) init() {
 		b.ResetTimeout = defaultResetTimeout
 	}
 
+	if b.HalfOpenMaxRequests == 0 {
+		b.HalfOpenMaxRequests = defaultHalfOpenMaxRequests
+	}
+
+	if b.HalfOpenSuccessThreshold == 0 {
+		b.HalfOpenSuccessThreshold = defaultHalfOpenSuccessThreshold
+	}
+
+	if b.Store != nil {
+		b.store = b.Store
+	} else {
+		b.store = newInMemoryStore()
+	}
+
+	if b.Now == nil {
+		b.Now = time.Now
+	}
+
+	if b.WindowSize > 0 {
+		if b.MinimumRequests == 0 {
+			b.MinimumRequests = defaultMinimumRequests
+		}
+		if b.FailureRatio == 0 {
+			b.FailureRatio = defaultFailureRatio
+		}
+		b.buckets = make([]windowBucket, numBuckets)
+		b.bucketStart = b.Now()
+	}
+
 	b.handles = make(chan struct{}, int(b.Concurrency))
 	for i := uint32(0); i < b.Concurrency; i++ {
 		b.handles <- struct{}{}
 	}
 }
 
+// GetState returns the circuit breaker's current state. Unlike calling IsClosed, IsOpen
+// and IsHalfOpen in turn, which each consult the store independently and so could observe
+// the breaker transition between calls, GetState reflects a single read.
+func (b *Breaker) GetState() State {
+	b.initer.Do(b.init)
+	return b.store.LoadState()
+}
+
 // IsClosed reports whether the circuit breaker is in the closed state
 func (b *Breaker) IsClosed() bool {
-	return atomic.LoadUint32(&b.state) == closed
+	return b.GetState() == StateClosed
 }
 
 // IsOpen reports whether the circuit breaker is in the open state
 func (b *Breaker) IsOpen() bool {
-	return atomic.LoadUint32(&b.state) == open
+	return b.GetState() == StateOpen
 }
 
 // IsHalfOpen reports whether the circuit breaker is in the half-open state
 func (b *Breaker) IsHalfOpen() bool {
-	return atomic.LoadUint32(&b.state) == halfopen
+	return b.GetState() == StateHalfOpen
+}
+
+// Counts is a snapshot of a Breaker's request and state counters, useful for metrics and
+// logging without requiring several separate, individually racy calls. Requests,
+// TotalSuccesses and TotalFailures are always this process's own counts. Under the default
+// in-memory Store, ConsecutiveFailures and LastStateChange describe the same process and
+// so agree with them; under a shared Store (see Breaker.Store) those two instead describe
+// the whole fleet, so the two halves of Counts can legitimately disagree and should not be
+// graphed or alerted on as a single consistent view in that configuration.
+type Counts struct {
+	// Requests is the number of requests that have been executed, as opposed to
+	// rejected, since the breaker was created.
+	Requests uint64
+
+	// TotalSuccesses is the number of executed requests that succeeded.
+	TotalSuccesses uint64
+
+	// TotalFailures is the number of executed requests that failed.
+	TotalFailures uint64
+
+	// ConsecutiveFailures is the current run of consecutive failures since the last
+	// success, the counter used by the Threshold policy. Sourced from the store, so under
+	// a shared Store this reflects the whole fleet rather than this process alone.
+	ConsecutiveFailures uint32
+
+	// ConcurrentInFlight is the number of requests currently executing.
+	ConcurrentInFlight uint32
+
+	// LastStateChange is the time of the breaker's most recent state transition. Sourced
+	// from the store, so under a shared Store this reflects the whole fleet rather than
+	// this process alone. Under the default in-memory Store, before any transition it is
+	// the time the store was created, on the breaker's first use.
+	LastStateChange time.Time
+}
+
+// Counts returns a snapshot of the breaker's request and state counters.
+func (b *Breaker) Counts() Counts {
+	b.initer.Do(b.init)
+
+	successes := atomic.LoadUint64(&b.totalSuccesses)
+	failures := atomic.LoadUint64(&b.totalFailures)
+
+	return Counts{
+		Requests:            successes + failures,
+		TotalSuccesses:      successes,
+		TotalFailures:       failures,
+		ConsecutiveFailures: b.store.ConsecutiveFailures(),
+		ConcurrentInFlight:  atomic.LoadUint32(&b.concurrentInFlight),
+		LastStateChange:     b.store.LastStateChange(),
+	}
 }
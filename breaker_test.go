@@ -8,6 +8,7 @@ package circuit
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -263,15 +264,359 @@ func TestBreakerContextCanceled(t *testing.T) {
 	}
 }
 
+func TestBreakerOpenWithFailureRate(t *testing.T) {
+	now := time.Now()
+
+	b := &Breaker{
+		Threshold:       100, // keep the consecutive-failure policy from tripping first
+		WindowSize:      10 * time.Second,
+		MinimumRequests: 4,
+		FailureRatio:    0.5,
+		Now:             func() time.Time { return now },
+	}
+
+	if !b.IsClosed() {
+		t.Fatalf("breaker was not in closed state")
+	}
+
+	// A success in between failures would reset the consecutive counter but should not
+	// prevent the rolling window from tripping on overall ratio.
+	if err := b.Do(context.Background(), failingAction()); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.Do(context.Background(), successfulAction()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.Do(context.Background(), failingAction()); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !b.IsClosed() {
+		t.Fatalf("breaker was not in closed state before MinimumRequests was reached")
+	}
+
+	if err := b.Do(context.Background(), failingAction()); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !b.IsOpen() {
+		t.Fatalf("breaker was not in open state")
+	}
+}
+
+// TestBreakerFailureRateWithTinyWindowSize guards against bucketDuration truncating to
+// zero for a WindowSize under numBuckets nanoseconds, which previously panicked with an
+// integer divide by zero the first time the clock was observed to have advanced.
+func TestBreakerFailureRateWithTinyWindowSize(t *testing.T) {
+	now := time.Now()
+
+	b := &Breaker{
+		Threshold:       100,
+		WindowSize:      1,
+		MinimumRequests: 100,
+		FailureRatio:    0.5,
+		Now:             func() time.Time { return now },
+	}
+
+	if err := b.Do(context.Background(), failingAction()); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if err := b.Do(context.Background(), failingAction()); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBreakerFailureRateBucketsAgeOut(t *testing.T) {
+	now := time.Now()
+
+	b := &Breaker{
+		Threshold:       100,
+		WindowSize:      10 * time.Second,
+		MinimumRequests: 2,
+		FailureRatio:    0.5,
+		Now:             func() time.Time { return now },
+	}
+
+	if err := b.Do(context.Background(), failingAction()); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.Do(context.Background(), failingAction()); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !b.IsOpen() {
+		t.Fatalf("breaker was not in open state")
+	}
+
+	// Close the breaker and move the clock past the whole window so the old failures roll
+	// out, then a single success at the start of the new window should not reopen the
+	// breaker. Were the two failures still counted, 2 of 3 requests would trip it again.
+	b.close()
+	now = now.Add(b.WindowSize * 2)
+
+	if err := b.Do(context.Background(), successfulAction()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !b.IsClosed() {
+		t.Fatalf("breaker was not in closed state")
+	}
+}
+
+func TestBreakerGetState(t *testing.T) {
+	b := &Breaker{Threshold: 1}
+
+	if b.GetState() != StateClosed {
+		t.Fatalf("got state %v, wanted %v", b.GetState(), StateClosed)
+	}
+
+	b.Do(context.Background(), failingAction())
+
+	if b.GetState() != StateOpen {
+		t.Fatalf("got state %v, wanted %v", b.GetState(), StateOpen)
+	}
+}
+
+func TestBreakerCounts(t *testing.T) {
+	b := &Breaker{Threshold: 100}
+
+	b.Do(context.Background(), successfulAction())
+	b.Do(context.Background(), successfulAction())
+	b.Do(context.Background(), failingAction())
+
+	counts := b.Counts()
+
+	if counts.Requests != 3 {
+		t.Errorf("got %d requests, wanted 3", counts.Requests)
+	}
+	if counts.TotalSuccesses != 2 {
+		t.Errorf("got %d successes, wanted 2", counts.TotalSuccesses)
+	}
+	if counts.TotalFailures != 1 {
+		t.Errorf("got %d failures, wanted 1", counts.TotalFailures)
+	}
+	if counts.ConsecutiveFailures != 1 {
+		t.Errorf("got %d consecutive failures, wanted 1", counts.ConsecutiveFailures)
+	}
+	if counts.ConcurrentInFlight != 0 {
+		t.Errorf("got %d in flight, wanted 0", counts.ConcurrentInFlight)
+	}
+	if counts.LastStateChange.IsZero() {
+		t.Errorf("expected LastStateChange to be set")
+	}
+}
+
+func TestBreakerOnStateChangeCalledOnOpenAndClose(t *testing.T) {
+	b := &Breaker{
+		Threshold:    1,
+		ResetTimeout: 20 * time.Millisecond,
+	}
+
+	// OnStateChange can fire from the time.AfterFunc goroutine that drives the reset
+	// timeout as well as from the goroutine calling Do, so transitions must be guarded
+	// rather than relied on to be synchronized by time.Sleep alone.
+	var mu sync.Mutex
+	var transitions []State
+	b.OnStateChange = func(from, to State, r OpenReason) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, from, to)
+	}
+
+	b.Do(context.Background(), failingAction())
+	if !b.IsOpen() {
+		t.Fatalf("breaker was not in open state")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if !b.IsHalfOpen() {
+		t.Fatalf("breaker was not in half-open state")
+	}
+
+	b.Do(context.Background(), successfulAction())
+	if !b.IsClosed() {
+		t.Fatalf("breaker was not in closed state")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []State{StateClosed, StateOpen, StateOpen, StateHalfOpen, StateHalfOpen, StateClosed}
+	if len(transitions) != len(want) {
+		t.Fatalf("got %v transitions, wanted %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transition %d: got %v, wanted %v", i, transitions[i], want[i])
+		}
+	}
+}
+
+func TestBreakerAcquireTimeoutExpires(t *testing.T) {
+	b := &Breaker{
+		Concurrency:    1,
+		AcquireTimeout: 20 * time.Millisecond,
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	quit := make(chan struct{})
+	defer close(quit)
+
+	go b.Do(context.Background(), blockingAction(&wg, quit))
+	wg.Wait()
+
+	start := time.Now()
+	err := b.Do(context.Background(), successfulAction())
+	elapsed := time.Since(start)
+
+	if err != ErrAcquireTimeout {
+		t.Fatalf("got error %v, wanted ErrAcquireTimeout", err)
+	}
+	if elapsed < b.AcquireTimeout {
+		t.Errorf("returned after %v, wanted at least %v", elapsed, b.AcquireTimeout)
+	}
+}
+
+func TestBreakerAcquireTimeoutHonoursContextCancellation(t *testing.T) {
+	b := &Breaker{
+		Concurrency:    1,
+		AcquireTimeout: time.Second,
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	quit := make(chan struct{})
+	defer close(quit)
+
+	go b.Do(context.Background(), blockingAction(&wg, quit))
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := b.Do(ctx, successfulAction()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, wanted context.DeadlineExceeded", err)
+	}
+}
+
+// TestBreakerCountsContextErrorFromFnAsFailure guards against conflating two different
+// things that can both look like a context error coming out of Do: ctx being done before
+// fn ran (not fn's fault, not counted) and fn itself returning a context-shaped error
+// having actually run (exactly the kind of failure the breaker exists to detect, e.g. an
+// upstream client bound to ctx timing out).
+func TestBreakerCountsContextErrorFromFnAsFailure(t *testing.T) {
+	b := &Breaker{Threshold: 3}
+
+	wrappedDeadlineExceeded := fmt.Errorf("upstream call failed: %w", context.DeadlineExceeded)
+
+	for i := 0; i < 3; i++ {
+		err := b.Do(context.Background(), func() error {
+			return wrappedDeadlineExceeded
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("call %d: got error %v, wanted wrapped context.DeadlineExceeded", i, err)
+		}
+	}
+
+	if !b.IsOpen() {
+		t.Fatalf("breaker did not trip after %d consecutive failures returned by fn", 3)
+	}
+	if got := b.Counts().TotalFailures; got != 3 {
+		t.Errorf("got %d total failures, wanted 3", got)
+	}
+}
+
+// TestBreakerHalfOpenTrialCancelledDuringAcquireReopens guards against a half-open trial
+// slot being stranded forever when ctx is cancelled while the admitted trial is still
+// waiting for a concurrency slot: with nothing to release the slot, the breaker would
+// otherwise stay half-open and reject every request indefinitely.
+func TestBreakerHalfOpenTrialCancelledDuringAcquireReopens(t *testing.T) {
+	b := &Breaker{
+		Concurrency:    1,
+		AcquireTimeout: time.Second,
+		ResetTimeout:   20 * time.Millisecond,
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	quit := make(chan struct{})
+
+	// Occupy the only concurrency slot so the half-open trial below has to wait for it.
+	go b.Do(context.Background(), blockingAction(&wg, quit))
+	wg.Wait()
+
+	b.reset()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := b.Do(ctx, successfulAction()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, wanted context.DeadlineExceeded", err)
+	}
+
+	if !b.IsOpen() {
+		t.Fatalf("breaker was left in half-open state instead of reopening")
+	}
+
+	// Free the slot and let the reset timer fire again; the breaker must still be able
+	// to recover instead of having stranded its only trial slot.
+	close(quit)
+
+	time.Sleep(40 * time.Millisecond)
+	if !b.IsHalfOpen() {
+		t.Fatalf("breaker did not return to half-open state after reopening")
+	}
+	if err := b.Do(context.Background(), successfulAction()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !b.IsClosed() {
+		t.Fatalf("breaker did not close after a successful trial")
+	}
+}
+
+func TestBreakerAcquireTimeoutSlotFreesInTime(t *testing.T) {
+	b := &Breaker{
+		Concurrency:    1,
+		AcquireTimeout: 200 * time.Millisecond,
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	quit := make(chan struct{})
+
+	go b.Do(context.Background(), blockingAction(&wg, quit))
+	wg.Wait()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(quit)
+	}()
+
+	if err := b.Do(context.Background(), successfulAction()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestBreakerOnResetCalledBeforeHalfOpenState(t *testing.T) {
 	b := &Breaker{
 		Threshold:    2,
 		ResetTimeout: 20 * time.Millisecond,
 	}
 
+	// OnReset fires from the time.AfterFunc goroutine that drives the reset timeout, so
+	// onResetCalled must be guarded rather than relied on to be synchronized by
+	// time.Sleep alone.
+	var mu sync.Mutex
 	onResetCalled := false
 
 	b.OnReset = func() {
+		mu.Lock()
+		defer mu.Unlock()
 		onResetCalled = true
 	}
 
@@ -296,9 +641,90 @@ func TestBreakerOnResetCalledBeforeHalfOpenState(t *testing.T) {
 	}
 
 	b.Do(context.Background(), func() error {
+		mu.Lock()
+		defer mu.Unlock()
 		if !onResetCalled {
 			t.Errorf("expected OnReset to have been called")
 		}
 		return nil
 	})
 }
+
+func TestBreakerHalfOpenAdmitsUpToMaxRequests(t *testing.T) {
+	b := &Breaker{HalfOpenMaxRequests: 2, HalfOpenSuccessThreshold: 2}
+	b.reset()
+
+	if err := b.Do(context.Background(), successfulAction()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !b.IsHalfOpen() {
+		t.Fatalf("breaker closed after 1 of 2 required successes")
+	}
+
+	if err := b.Do(context.Background(), successfulAction()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !b.IsClosed() {
+		t.Fatalf("breaker was not in closed state after 2 of 2 required successes")
+	}
+}
+
+func TestBreakerHalfOpenRejectsRequestsBeyondMax(t *testing.T) {
+	b := &Breaker{HalfOpenMaxRequests: 2, HalfOpenSuccessThreshold: 2}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	quit := make(chan struct{})
+	defer close(quit)
+
+	b.reset()
+	go b.Do(context.Background(), blockingAction(&wg, quit))
+	go b.Do(context.Background(), blockingAction(&wg, quit))
+
+	// Wait for both trial slots to be claimed and blocked.
+	wg.Wait()
+
+	done := false
+	if err := b.Do(context.Background(), func() error { done = true; return nil }); err != ErrCircuitOpen {
+		t.Fatalf("got error %v, wanted ErrCircuitOpen for a request beyond HalfOpenMaxRequests", err)
+	}
+	if done {
+		t.Errorf("third action executed, wanted it to be rejected")
+	}
+}
+
+func TestBreakerHalfOpenSuccessDoesNotReCloseAfterReopening(t *testing.T) {
+	b := &Breaker{HalfOpenMaxRequests: 2, HalfOpenSuccessThreshold: 2, ResetTimeout: time.Hour}
+	b.reset()
+
+	// Simulate a trial failure reopening the circuit after a concurrent trial had
+	// already been admitted but had not yet recorded its success.
+	b.open(OpenReasonTrial)
+
+	from, closed := b.store.RecordTrialSuccess(b.HalfOpenSuccessThreshold)
+	if closed {
+		t.Fatalf("RecordTrialSuccess closed the breaker, wanted it to be a no-op once the circuit had reopened")
+	}
+	if from != StateOpen {
+		t.Errorf("got prior state %v, wanted StateOpen", from)
+	}
+	if !b.IsOpen() {
+		t.Errorf("breaker was not in open state")
+	}
+}
+
+func TestBreakerHalfOpenReopensOnFirstFailure(t *testing.T) {
+	b := &Breaker{HalfOpenMaxRequests: 3, HalfOpenSuccessThreshold: 3, ResetTimeout: time.Hour}
+	b.reset()
+
+	if err := b.Do(context.Background(), successfulAction()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.Do(context.Background(), failingAction()); err != errFail {
+		t.Fatalf("got error %v, wanted errFail", err)
+	}
+
+	if !b.IsOpen() {
+		t.Fatalf("breaker did not reopen after a failing half-open trial")
+	}
+}
@@ -0,0 +1,42 @@
+/*
+This is free and unencumbered software released into the public domain. For more
+information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package circuit
+
+import "context"
+
+// Execute runs fn through the breaker b and returns the value fn produced together with
+// any error, without requiring the caller to capture the result in an external variable.
+// It is otherwise identical to Breaker.Do: an error from fn counts as a failure (subject
+// to IsSuccessful), ErrTooManyConcurrent is returned if there is no free concurrency slot
+// (or, with AcquireTimeout set, ErrAcquireTimeout or ctx's error once the wait gives up),
+// and ErrCircuitOpen is returned if the breaker is open.
+func Execute[T any](ctx context.Context, b *Breaker, fn func(context.Context) (T, error)) (T, error) {
+	var result T
+	err := b.Do(ctx, func() error {
+		var fnErr error
+		result, fnErr = fn(ctx)
+		return fnErr
+	})
+	return result, err
+}
+
+// TypedBreaker wraps a *Breaker to provide an Execute method that returns a value of type
+// T alongside the error, for callers who always want the same result type back from a
+// given breaker. It has no state of its own; all configuration and trip state live on the
+// wrapped Breaker, so the Breaker may be shared with other callers using Do or Execute.
+type TypedBreaker[T any] struct {
+	*Breaker
+}
+
+// NewTypedBreaker wraps b as a TypedBreaker[T].
+func NewTypedBreaker[T any](b *Breaker) *TypedBreaker[T] {
+	return &TypedBreaker[T]{Breaker: b}
+}
+
+// Execute runs fn through the wrapped breaker. See the Execute function for details.
+func (t *TypedBreaker[T]) Execute(ctx context.Context, fn func(context.Context) (T, error)) (T, error) {
+	return Execute[T](ctx, t.Breaker, fn)
+}
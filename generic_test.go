@@ -0,0 +1,86 @@
+/*
+This is free and unencumbered software released into the public domain. For more
+information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package circuit
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestExecuteReturnsValue(t *testing.T) {
+	b := &Breaker{}
+
+	got, err := Execute(context.Background(), b, func(context.Context) (int, error) {
+		return 42, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != 42 {
+		t.Errorf("got %d, wanted 42", got)
+	}
+}
+
+func TestExecutePropagatesError(t *testing.T) {
+	b := &Breaker{}
+
+	got, err := Execute(context.Background(), b, func(context.Context) (int, error) {
+		return 0, errFail
+	})
+	if err != errFail {
+		t.Fatalf("got error %v, wanted errFail", err)
+	}
+	if got != 0 {
+		t.Errorf("got %d, wanted zero value", got)
+	}
+}
+
+func TestTypedBreakerExecute(t *testing.T) {
+	tb := NewTypedBreaker[string](&Breaker{})
+
+	got, err := tb.Execute(context.Background(), func(context.Context) (string, error) {
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "ok" {
+		t.Errorf("got %q, wanted %q", got, "ok")
+	}
+}
+
+func TestBreakerIsSuccessfulOverridesFailure(t *testing.T) {
+	b := &Breaker{
+		Threshold: 2,
+		IsSuccessful: func(err error) bool {
+			return errors.Is(err, errIgnorable)
+		},
+	}
+
+	// Five calls that return errIgnorable should not count towards the threshold because
+	// IsSuccessful classifies them as successes.
+	for i := 0; i < 5; i++ {
+		if err := b.Do(context.Background(), func() error { return errIgnorable }); err != errIgnorable {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !b.IsClosed() {
+			t.Fatalf("call %d: breaker was not in closed state", i)
+		}
+	}
+
+	// A genuine failure still counts normally.
+	b.Do(context.Background(), failingAction())
+	if err := b.Do(context.Background(), failingAction()); err != errFail {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !b.IsOpen() {
+		t.Fatalf("breaker was not in open state")
+	}
+}
+
+var errIgnorable = errors.New("ignorable")
@@ -0,0 +1,125 @@
+/*
+This is free and unencumbered software released into the public domain. For more
+information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package circuit
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// Retry composes a retry-with-backoff policy with a Breaker. It exists so that callers
+// who want both patterns do not have to wrap Breaker.Do in their own retry loop, which
+// tends to race with the breaker's own half-open trial accounting.
+type Retry struct {
+	// Attempts is the maximum number of times Do will call the breaker. If zero or
+	// negative a default of 1 is assumed, i.e. no retries.
+	Attempts int
+
+	// Backoff returns how long to wait before the given attempt, starting at 0 for the
+	// delay before the second call. If nil, Do retries immediately.
+	Backoff func(attempt int) time.Duration
+
+	// Jitter is the proportion, in the range [0, 1], of each backoff duration that is
+	// randomised. A Backoff result of d is adjusted by up to +/- d*Jitter. If zero no
+	// jitter is applied.
+	Jitter float64
+
+	// Rand returns a float64 in [0, 1) and is used to compute jitter. If nil
+	// rand.Float64 is used. It exists as a seam so tests can make jitter deterministic.
+	Rand func() float64
+}
+
+// Do runs fn through b.Do, retrying on failure up to Attempts times with a delay between
+// attempts governed by Backoff and Jitter. ErrCircuitOpen is never retried, since a retry
+// would just be rejected again until the breaker's reset timeout elapses; it is returned
+// to the caller immediately. Once ctx itself is done, Do also stops and returns the last
+// error rather than retrying, since every subsequent b.Do call would fail the same way;
+// this is checked against ctx directly rather than against the shape of the returned
+// error, so a transient context.Canceled or context.DeadlineExceeded that fn produces on
+// its own (for example from a per-call timeout unrelated to ctx) is still retried. Any
+// error that b's IsSuccessful classifies as a success is also returned immediately, since
+// it is not the kind of transient failure Retry is meant to paper over.
+func (r Retry) Do(ctx context.Context, b *Breaker, fn func() error) error {
+	attempts := r.Attempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		err = b.Do(ctx, fn)
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, ErrCircuitOpen):
+			return err
+		case ctx.Err() != nil:
+			// ctx is done, so every subsequent b.Do call would fail the same way
+			// without even reaching fn; retrying further would be pointless.
+			return err
+		case b.isSuccessful(err):
+			// b's IsSuccessful classifies this error as a success, so it is not the
+			// kind of transient failure Retry should retry.
+			return err
+		}
+
+		if attempt == attempts-1 {
+			break
+		}
+
+		if werr := r.wait(ctx, attempt); werr != nil {
+			return werr
+		}
+	}
+
+	return err
+}
+
+// wait blocks for the backoff delay of the given attempt, returning early with ctx's
+// error if ctx is done first.
+func (r Retry) wait(ctx context.Context, attempt int) error {
+	delay := r.delay(attempt)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// delay computes the backoff duration for attempt, applying jitter if configured.
+func (r Retry) delay(attempt int) time.Duration {
+	if r.Backoff == nil {
+		return 0
+	}
+
+	d := r.Backoff(attempt)
+	if d <= 0 || r.Jitter <= 0 {
+		return d
+	}
+
+	randFloat64 := r.Rand
+	if randFloat64 == nil {
+		randFloat64 = rand.Float64
+	}
+
+	spread := float64(d) * r.Jitter
+	offset := spread*2*randFloat64() - spread
+	d += time.Duration(offset)
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,163 @@
+/*
+This is free and unencumbered software released into the public domain. For more
+information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package circuit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRetrySucceedsAfterFailures(t *testing.T) {
+	b := &Breaker{Threshold: 10}
+	r := Retry{Attempts: 3}
+
+	calls := 0
+	err := r.Do(context.Background(), b, func() error {
+		calls++
+		if calls < 3 {
+			return errFail
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, wanted 3", calls)
+	}
+}
+
+func TestRetryExhaustsAttempts(t *testing.T) {
+	b := &Breaker{Threshold: 10}
+	r := Retry{Attempts: 3}
+
+	calls := 0
+	err := r.Do(context.Background(), b, func() error {
+		calls++
+		return errFail
+	})
+	if err != errFail {
+		t.Fatalf("got error %v, wanted errFail", err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, wanted 3", calls)
+	}
+}
+
+func TestRetryDoesNotRetryCircuitOpen(t *testing.T) {
+	b := &Breaker{Threshold: 1}
+	b.open(OpenReasonThreshold)
+
+	r := Retry{Attempts: 5}
+
+	calls := 0
+	err := r.Do(context.Background(), b, func() error {
+		calls++
+		return nil
+	})
+	if err != ErrCircuitOpen {
+		t.Fatalf("got error %v, wanted ErrCircuitOpen", err)
+	}
+	if calls != 0 {
+		t.Errorf("got %d calls, wanted 0 because the breaker was open", calls)
+	}
+}
+
+func TestRetryHonoursContextCancellationDuringBackoff(t *testing.T) {
+	b := &Breaker{Threshold: 10}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := Retry{
+		Attempts: 3,
+		Backoff:  func(attempt int) time.Duration { return time.Hour },
+	}
+
+	calls := 0
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	err := r.Do(ctx, b, func() error {
+		calls++
+		return errFail
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, wanted context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, wanted 1 before the backoff wait was cancelled", calls)
+	}
+}
+
+func TestRetryRetriesFnsOwnContextError(t *testing.T) {
+	b := &Breaker{Threshold: 10}
+	r := Retry{Attempts: 3}
+
+	// fn returns a context-shaped error of its own (e.g. a per-call timeout unrelated to
+	// ctx) while ctx itself is never cancelled. Retry must still retry this, since it is
+	// a genuine transient failure rather than ctx having given up.
+	calls := 0
+	err := r.Do(context.Background(), b, func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("downstream call failed: %w", context.DeadlineExceeded)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("got %d calls, wanted 3", calls)
+	}
+}
+
+func TestRetryDoesNotRetryErrorsClassifiedAsSuccessful(t *testing.T) {
+	b := &Breaker{
+		Threshold: 10,
+		IsSuccessful: func(err error) bool {
+			return errors.Is(err, errIgnorable)
+		},
+	}
+	r := Retry{Attempts: 5}
+
+	calls := 0
+	err := r.Do(context.Background(), b, func() error {
+		calls++
+		return errIgnorable
+	})
+	if err != errIgnorable {
+		t.Fatalf("got error %v, wanted errIgnorable", err)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, wanted 1 because errIgnorable is not a transient failure", calls)
+	}
+}
+
+func TestRetryAppliesJitterWithinBounds(t *testing.T) {
+	r := Retry{
+		Backoff: func(attempt int) time.Duration { return time.Second },
+		Jitter:  0.5,
+		Rand:    func() float64 { return 1 },
+	}
+
+	got := r.delay(0)
+	want := 1500 * time.Millisecond
+	if got != want {
+		t.Errorf("got delay %v, wanted %v", got, want)
+	}
+
+	r.Rand = func() float64 { return 0 }
+	got = r.delay(0)
+	want = 500 * time.Millisecond
+	if got != want {
+		t.Errorf("got delay %v, wanted %v", got, want)
+	}
+}
@@ -0,0 +1,201 @@
+/*
+This is free and unencumbered software released into the public domain. For more
+information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package circuit
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StateStore is the backend a Breaker delegates its state to: which of closed, open or
+// half-open it is in, its consecutive failure count, and how many half-open trial slots
+// and successes have been claimed. The default, used when Breaker.Store is nil, keeps this
+// in process memory. Implementations backed by Redis or another shared store let a whole
+// fleet of instances guarding the same upstream dependency act as a single logical circuit
+// breaker, rather than each instance tripping and probing independently.
+//
+// The rolling failure-rate window controlled by Breaker.WindowSize is not part of this
+// contract and is always kept in process memory, regardless of which StateStore is in use;
+// see Breaker.WindowSize.
+type StateStore interface {
+	// LoadState returns the store's current view of the breaker's state.
+	LoadState() State
+
+	// RecordSuccess records a successful call, resetting the consecutive failure count.
+	RecordSuccess()
+
+	// RecordFailure records a failed call and returns the updated consecutive failure
+	// count.
+	RecordFailure() uint32
+
+	// ConsecutiveFailures returns the store's current consecutive failure count.
+	ConsecutiveFailures() uint32
+
+	// LastStateChange returns the time of the store's most recent state transition.
+	LastStateChange() time.Time
+
+	// TryAcquireTrial attempts to claim one of up to max half-open trial slots. It
+	// reports whether the caller was granted a slot and should execute the trial
+	// request. If it returns false, max trials have already been claimed for this
+	// half-open period and the request should be rejected as though the circuit were
+	// open.
+	TryAcquireTrial(max uint32) bool
+
+	// RecordTrialSuccess records a successful half-open trial and, once threshold
+	// successes have been recorded during this half-open period, transitions the store
+	// to the closed state in the same atomic step, returning the prior state and
+	// reporting whether this call performed the transition. Folding the threshold check
+	// into the same transition as Open and Close guards against a concurrent trial
+	// failure reopening the circuit just as another trial's success would otherwise have
+	// closed it.
+	RecordTrialSuccess(threshold uint32) (from State, closed bool)
+
+	// Open transitions the store to the open state, resetting the failure count and the
+	// trial slot. It returns the state the store was in beforehand and reports whether
+	// this call performed the transition; a false return means the store was already open
+	// and the caller should not repeat open's side effects such as invoking OnOpen or
+	// arming the reset timer.
+	Open(reason OpenReason) (from State, ok bool)
+
+	// Close transitions the store to the closed state, returning the prior state and
+	// reporting whether this call performed the transition.
+	Close() (from State, ok bool)
+
+	// Reset transitions the store to the half-open state, usually called after the reset
+	// timeout elapses, returning the prior state and reporting whether this call
+	// performed the transition.
+	Reset() (from State, ok bool)
+}
+
+// inMemoryStore is the default StateStore, keeping all breaker state in process memory.
+// It reproduces the atomic-field bookkeeping the breaker used before StateStore existed.
+type inMemoryStore struct {
+	// mu ensures only one state transition can occur at a time
+	mu sync.Mutex
+
+	// state is the current state of the circuit breaker: closed, open, half-open
+	state uint32
+
+	// failures is a count of consecutive failures
+	failures uint32
+
+	// trialsStarted counts the half-open trial requests that have been admitted during
+	// the current half-open period, up to the configured HalfOpenMaxRequests.
+	trialsStarted uint32
+
+	// trialSuccesses counts the half-open trials that have succeeded during the
+	// current half-open period.
+	trialSuccesses uint32
+
+	// lastStateChange holds the unix nanosecond timestamp of the most recent transition,
+	// set while mu is held.
+	lastStateChange int64
+}
+
+func newInMemoryStore() *inMemoryStore {
+	return &inMemoryStore{lastStateChange: time.Now().UnixNano()}
+}
+
+func (s *inMemoryStore) LoadState() State {
+	return State(atomic.LoadUint32(&s.state))
+}
+
+func (s *inMemoryStore) RecordSuccess() {
+	atomic.StoreUint32(&s.failures, 0)
+}
+
+func (s *inMemoryStore) RecordFailure() uint32 {
+	return atomic.AddUint32(&s.failures, 1)
+}
+
+func (s *inMemoryStore) ConsecutiveFailures() uint32 {
+	return atomic.LoadUint32(&s.failures)
+}
+
+func (s *inMemoryStore) LastStateChange() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&s.lastStateChange))
+}
+
+func (s *inMemoryStore) TryAcquireTrial(max uint32) bool {
+	for {
+		started := atomic.LoadUint32(&s.trialsStarted)
+		if started >= max {
+			return false
+		}
+		if atomic.CompareAndSwapUint32(&s.trialsStarted, started, started+1) {
+			return true
+		}
+	}
+}
+
+func (s *inMemoryStore) RecordTrialSuccess(threshold uint32) (State, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from := State(atomic.LoadUint32(&s.state))
+	if from != StateHalfOpen {
+		// A concurrent trial failure already reopened (or closed) the circuit; this
+		// success belongs to a half-open period that no longer exists.
+		return from, false
+	}
+
+	if atomic.AddUint32(&s.trialSuccesses, 1) < threshold {
+		return from, false
+	}
+
+	atomic.StoreUint32(&s.state, uint32(StateClosed))
+	atomic.StoreUint32(&s.failures, 0)
+	atomic.StoreUint32(&s.trialsStarted, 0)
+	atomic.StoreUint32(&s.trialSuccesses, 0)
+	atomic.StoreInt64(&s.lastStateChange, time.Now().UnixNano())
+	return from, true
+}
+
+func (s *inMemoryStore) Open(reason OpenReason) (State, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from := State(atomic.LoadUint32(&s.state))
+	if from == StateOpen {
+		return from, false
+	}
+	atomic.StoreUint32(&s.state, uint32(StateOpen))
+	atomic.StoreUint32(&s.failures, 0)
+	atomic.StoreUint32(&s.trialsStarted, 0)
+	atomic.StoreUint32(&s.trialSuccesses, 0)
+	atomic.StoreInt64(&s.lastStateChange, time.Now().UnixNano())
+	return from, true
+}
+
+func (s *inMemoryStore) Close() (State, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from := State(atomic.LoadUint32(&s.state))
+	if from == StateClosed {
+		return from, false
+	}
+	atomic.StoreUint32(&s.state, uint32(StateClosed))
+	atomic.StoreUint32(&s.failures, 0)
+	atomic.StoreUint32(&s.trialsStarted, 0)
+	atomic.StoreUint32(&s.trialSuccesses, 0)
+	atomic.StoreInt64(&s.lastStateChange, time.Now().UnixNano())
+	return from, true
+}
+
+func (s *inMemoryStore) Reset() (State, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	from := State(atomic.LoadUint32(&s.state))
+	if from == StateHalfOpen {
+		return from, false
+	}
+	atomic.StoreUint32(&s.state, uint32(StateHalfOpen))
+	atomic.StoreUint32(&s.failures, 0)
+	atomic.StoreUint32(&s.trialsStarted, 0)
+	atomic.StoreUint32(&s.trialSuccesses, 0)
+	atomic.StoreInt64(&s.lastStateChange, time.Now().UnixNano())
+	return from, true
+}
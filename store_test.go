@@ -0,0 +1,78 @@
+/*
+This is free and unencumbered software released into the public domain. For more
+information, see <http://unlicense.org/> or the accompanying UNLICENSE file.
+*/
+
+package circuit
+
+import (
+	"context"
+	"testing"
+)
+
+// countingStore wraps an inMemoryStore so tests can assert that Breaker consults and
+// mutates the configured Store rather than falling back to its own default.
+type countingStore struct {
+	*inMemoryStore
+	opens int
+}
+
+func newCountingStore() *countingStore {
+	return &countingStore{inMemoryStore: newInMemoryStore()}
+}
+
+func (s *countingStore) Open(reason OpenReason) (State, bool) {
+	from, opened := s.inMemoryStore.Open(reason)
+	if opened {
+		s.opens++
+	}
+	return from, opened
+}
+
+func TestBreakerUsesConfiguredStore(t *testing.T) {
+	store := newCountingStore()
+	b := &Breaker{
+		Threshold: 2,
+		Store:     store,
+	}
+
+	b.Do(context.Background(), failingAction())
+	b.Do(context.Background(), failingAction())
+
+	if !b.IsOpen() {
+		t.Fatalf("breaker was not in open state")
+	}
+
+	if store.opens != 1 {
+		t.Errorf("got %d opens recorded by the store, wanted 1", store.opens)
+	}
+}
+
+// noTrialStore never grants the half-open trial slot, simulating another node in a
+// cluster having already claimed it.
+type noTrialStore struct {
+	*inMemoryStore
+}
+
+func (s *noTrialStore) TryAcquireTrial(max uint32) bool {
+	return false
+}
+
+func TestBreakerDefersHalfOpenTrialToStore(t *testing.T) {
+	b := &Breaker{Store: &noTrialStore{inMemoryStore: newInMemoryStore()}}
+
+	b.reset()
+
+	done := false
+	err := b.Do(context.Background(), func() error {
+		done = true
+		return nil
+	})
+
+	if err != ErrCircuitOpen {
+		t.Fatalf("got error %v, wanted ErrCircuitOpen", err)
+	}
+	if done {
+		t.Errorf("action executed, wanted it not to be executed because the store withheld the trial slot")
+	}
+}